@@ -1,9 +1,13 @@
 package rush
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -13,17 +17,43 @@ var allMethods = []string{
 	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
 }
 
+// OptionsHandler serves an auto-handled OPTIONS request. allowedMethods is
+// the same method list reported in the response's Allow header, letting
+// implementations answer CORS preflight requests (Access-Control-Allow-*)
+// without re-deriving which methods the matched route supports.
+type OptionsHandler interface {
+	ServeOptions(w http.ResponseWriter, r *http.Request, allowedMethods []string)
+}
+
+// OptionsHandlerFunc adapts a function to an OptionsHandler.
+type OptionsHandlerFunc func(w http.ResponseWriter, r *http.Request, allowedMethods []string)
+
+func (f OptionsHandlerFunc) ServeOptions(w http.ResponseWriter, r *http.Request, allowedMethods []string) {
+	f(w, r, allowedMethods)
+}
+
 type Router struct {
 	// Configuration handlers
 	NotFound              http.Handler
 	MethodNotAllowed      http.Handler
-	AutoOptions           http.Handler
+	AutoOptions           OptionsHandler
 	RedirectTrailingSlash bool
+	// RedirectFixedPath, when a request path only matches a route once
+	// cleaned (collapsing "//" and resolving "." / ".."), issues a
+	// redirect to the cleaned path instead of serving it directly.
+	RedirectFixedPath bool
+	// TrustForwardedHeaders makes Host and Schemes matching honor the
+	// X-Forwarded-Host and X-Forwarded-Proto headers over r.Host and
+	// r.TLS. Only enable this behind a trusted reverse proxy that sets
+	// (and strips client-supplied) these headers itself.
+	TrustForwardedHeaders bool
 
 	// Internal state
 	routes      *trie
 	middlewares []Middleware
 	prefix      string
+	host        string
+	schemes     []string
 	handler     http.Handler
 	isRoot      bool
 }
@@ -34,14 +64,29 @@ func New() *Router {
 		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		}),
-		AutoOptions: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AutoOptions: OptionsHandlerFunc(func(w http.ResponseWriter, r *http.Request, allowedMethods []string) {
 			w.WriteHeader(http.StatusNoContent)
 		}),
-		routes: &trie{root: newNode("/")},
+		routes: &trie{root: newNode("/"), names: make(map[string]*node)},
 		isRoot: true,
 	}
 }
 
+// Route is returned by Handle (and its Get/Post/... shorthands) so the
+// route can be named for reverse URL generation via Router.URL.
+type Route struct {
+	router *Router
+	node   *node
+}
+
+// Name assigns a name to the route, so Router.URL can later build a URL
+// for it. Registering another route under the same name replaces it.
+func (rt *Route) Name(name string) *Route {
+	rt.node.name = name
+	rt.router.routes.names[name] = rt.node
+	return rt
+}
+
 func (r *Router) Use(middlewares ...Middleware) {
 	if r.handler != nil {
 		panic("rush: all root-level middlewares must be defined before routes")
@@ -57,12 +102,12 @@ func (r *Router) cloneChain() []Middleware {
 }
 
 func (r *Router) Group(fn func(r *Router)) {
-	sub := &Router{routes: r.routes, prefix: r.prefix, middlewares: r.cloneChain()}
+	sub := &Router{routes: r.routes, prefix: r.prefix, middlewares: r.cloneChain(), host: r.host, schemes: r.schemes}
 	fn(sub)
 }
 
 func (r *Router) GroupWithPrefix(prefix string, fn func(r *Router)) {
-	sub := &Router{routes: r.routes, prefix: r.prefix + prefix, middlewares: r.cloneChain()}
+	sub := &Router{routes: r.routes, prefix: r.prefix + prefix, middlewares: r.cloneChain(), host: r.host, schemes: r.schemes}
 	fn(sub)
 }
 
@@ -71,21 +116,70 @@ func (r *Router) With(middlewares ...Middleware) *Router {
 		routes:      r.routes,
 		prefix:      r.prefix,
 		middlewares: append(r.cloneChain(), middlewares...),
+		host:        r.host,
+		schemes:     r.schemes,
+	}
+}
+
+// Host returns a sub-router whose routes only match requests whose host
+// (see TrustForwardedHeaders) matches pattern. pattern may contain
+// "{name}" labels, e.g. "{tenant}.example.com", whose captured values
+// are merged into PathValue alongside the route's own path params.
+func (r *Router) Host(pattern string) *Router {
+	return &Router{routes: r.routes, prefix: r.prefix, middlewares: r.cloneChain(), host: pattern, schemes: r.schemes}
+}
+
+// Schemes returns a sub-router whose routes only match requests made
+// over one of the given schemes ("http", "https").
+func (r *Router) Schemes(schemes ...string) *Router {
+	normalized := make([]string, len(schemes))
+	for i, s := range schemes {
+		normalized[i] = strings.ToLower(s)
+	}
+	return &Router{routes: r.routes, prefix: r.prefix, middlewares: r.cloneChain(), host: r.host, schemes: normalized}
+}
+
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc, methods ...string) *Route {
+	return r.Handle(pattern, handler, methods...)
+}
+
+// Mount delegates every request under prefix to h, stripping the mount
+// prefix from the request URL first (so h sees MountedPath(r) the way it
+// would if it were serving from "/"). Method handling, 405s, and OPTIONS
+// for the mounted subtree are entirely up to h - the parent router's trie
+// only matches the prefix and hands off.
+func (r *Router) Mount(prefix string, h http.Handler) {
+	if r.isRoot && r.handler == nil {
+		r.handler = chain(r.middlewares, http.HandlerFunc(r.handleRequest))
+	}
+
+	handler := http.StripPrefix(r.prefix+prefix, h)
+	if !r.isRoot {
+		handler = chain(r.middlewares, handler)
+	}
+
+	node := r.routes.mount(r.host, r.prefix+prefix, handler)
+	if len(r.schemes) > 0 {
+		node.mountSchemes = r.schemes
 	}
 }
 
-func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc, methods ...string) {
-	r.Handle(pattern, handler, methods...)
+// MountedPath returns the sub-path of r relative to the prefix it was
+// mounted under - equivalent to r.URL.Path inside a Mount handler, since
+// Mount already strips its prefix before delegating.
+func MountedPath(r *http.Request) string {
+	return r.URL.Path
 }
 
-func (r *Router) Handle(pattern string, handler http.Handler, methods ...string) {
+func (r *Router) Handle(pattern string, handler http.Handler, methods ...string) *Route {
 	// Normalize method names to uppercase
 	for i, m := range methods {
 		methods[i] = strings.ToUpper(m)
 	}
 
 	// Auto-add HEAD method when GET is specified
-	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+	autoHead := slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead)
+	if autoHead {
 		methods = append(methods, http.MethodHead)
 	}
 
@@ -104,7 +198,79 @@ func (r *Router) Handle(pattern string, handler http.Handler, methods ...string)
 		handler = chain(r.middlewares, handler)
 	}
 
-	r.routes.insert(r.prefix+pattern, handler, methods...)
+	full := r.prefix + pattern
+	node := r.routes.insert(r.host, full, handler, methods...)
+	if autoHead {
+		// The GET handler runs the full request, but its body must not
+		// reach the client for an implicit HEAD - only Content-Length.
+		node.handlers[http.MethodHead] = discardBody(handler)
+	}
+	node.pattern = full
+	node.host = r.host
+
+	if node.middlewares == nil {
+		node.middlewares = make(map[string][]Middleware)
+	}
+	if node.schemes == nil {
+		node.schemes = make(map[string][]string)
+	}
+	for _, method := range methods {
+		node.middlewares[method] = r.middlewares
+		if len(r.schemes) > 0 {
+			node.schemes[method] = r.schemes
+		} else {
+			delete(node.schemes, method)
+		}
+	}
+
+	return &Route{router: r, node: node}
+}
+
+// headResponseWriter wraps an http.ResponseWriter so that, for an
+// auto-registered HEAD route, the wrapped GET handler's body is tallied
+// but never written to the client - only its length, via Content-Length,
+// reaches them.
+type headResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	statusCode  int
+	length      int
+}
+
+func (w *headResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.statusCode = code
+}
+
+func (w *headResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.length += len(p)
+	return len(p), nil
+}
+
+func (w *headResponseWriter) flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.Header().Get("Content-Length") == "" {
+		w.Header().Set("Content-Length", strconv.Itoa(w.length))
+	}
+	w.ResponseWriter.WriteHeader(w.statusCode)
+}
+
+// discardBody runs h against a headResponseWriter so its body is computed
+// (for Content-Length) but discarded before reaching the client.
+func discardBody(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		hw := &headResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(hw, rq)
+		hw.flush()
+	})
 }
 
 func chain(middlewares []Middleware, handler http.Handler) http.Handler {
@@ -114,32 +280,92 @@ func chain(middlewares []Middleware, handler http.Handler) http.Handler {
 	return handler
 }
 
-func (r *Router) Get(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodGet)
+func (r *Router) Get(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodGet)
+}
+
+func (r *Router) Head(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodHead)
 }
 
-func (r *Router) Head(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodHead)
+func (r *Router) Post(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodPost)
 }
 
-func (r *Router) Post(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodPost)
+func (r *Router) Put(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodPut)
 }
 
-func (r *Router) Put(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodPut)
+func (r *Router) Patch(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodPatch)
 }
 
-func (r *Router) Patch(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodPatch)
+func (r *Router) Delete(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodDelete)
 }
 
-func (r *Router) Delete(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodDelete)
+func (r *Router) Options(pattern string, handlerFunc http.HandlerFunc) *Route {
+	return r.Handle(pattern, handlerFunc, http.MethodOptions)
 }
 
-func (r *Router) Options(pattern string, handlerFunc http.HandlerFunc) {
-	r.Handle(pattern, handlerFunc, http.MethodOptions)
+// Walk calls fn for every registered route (excluding mounted subtrees),
+// reporting its method, host pattern (empty if the route carries no host
+// restriction), original pattern, handler, and the middleware chain it was
+// registered with. It stops and returns the first error fn returns.
+func (r *Router) Walk(fn func(method, host, pattern string, handler http.Handler, middlewares []Middleware) error) error {
+	if err := r.routes.root.walk(fn); err != nil {
+		return err
+	}
+
+	hosts := make([]string, 0, len(r.routes.hostRoots))
+	for host := range r.routes.hostRoots {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+	for _, host := range hosts {
+		if err := r.routes.hostRoots[host].walk(fn); err != nil {
+			return err
+		}
+	}
+
+	for _, hr := range r.routes.hostParams {
+		if err := hr.root.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// URL builds a URL for the named route (see Route.Name), substituting
+// "{param}" placeholders with params in order and URL-escaping each value.
+// It also returns the route's host pattern (empty if it carries no host
+// restriction via Router.Host), since that isn't part of the path itself.
+func (r *Router) URL(name string, params ...string) (path, host string, err error) {
+	node, ok := r.routes.names[name]
+	if !ok {
+		return "", "", fmt.Errorf("rush: no route named %q", name)
+	}
+
+	segments := splitPath(node.pattern)
+	var b strings.Builder
+	pi := 0
+	for _, seg := range segments {
+		b.WriteByte('/')
+		if strings.HasPrefix(seg, "{") {
+			if pi >= len(params) {
+				return "", "", fmt.Errorf("rush: not enough params for route %q", name)
+			}
+			b.WriteString(url.PathEscape(params[pi]))
+			pi++
+			continue
+		}
+		b.WriteString(seg)
+	}
+	if pi != len(params) {
+		return "", "", fmt.Errorf("rush: too many params for route %q", name)
+	}
+
+	return b.String(), node.host, nil
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
@@ -167,27 +393,78 @@ func needsCleaning(path string) bool {
 	return false
 }
 
+// requestHost returns the host used for Host matching: r.Host by default,
+// or X-Forwarded-Host when TrustForwardedHeaders is set and the header is
+// present. Any port suffix is stripped either way.
+func (r *Router) requestHost(rq *http.Request) string {
+	host := rq.Host
+	if r.TrustForwardedHeaders {
+		if fh := rq.Header.Get("X-Forwarded-Host"); fh != "" {
+			host = fh
+		}
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// requestScheme returns the scheme used for Schemes matching: "https" when
+// rq.TLS is set, otherwise "http" - or X-Forwarded-Proto when
+// TrustForwardedHeaders is set and the header is present.
+func (r *Router) requestScheme(rq *http.Request) string {
+	if r.TrustForwardedHeaders {
+		if fp := rq.Header.Get("X-Forwarded-Proto"); fp != "" {
+			return strings.ToLower(fp)
+		}
+	}
+	if rq.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
 func (r *Router) handleRequest(w http.ResponseWriter, rq *http.Request) {
 	urlPath := rq.URL.Path
+	cleaned := urlPath
 	if needsCleaning(urlPath) {
-		urlPath = path.Clean(urlPath)
+		cleaned = path.Clean(urlPath)
 	}
 
-	match := r.routes.lookup(urlPath, rq)
+	match := r.routes.lookup(r.requestHost(rq), cleaned, rq)
+	if match != nil {
+		schemes := match.schemes[rq.Method]
+		if match.subtreeHandler != nil {
+			schemes = match.mountSchemes
+		}
+		if len(schemes) > 0 && !slices.Contains(schemes, r.requestScheme(rq)) {
+			match = nil
+		}
+	}
 	if match == nil {
 		r.NotFound.ServeHTTP(w, rq)
 		return
 	}
 
-	if r.RedirectTrailingSlash && urlPath != "/" && strings.HasSuffix(rq.URL.Path, "/") {
-		code := http.StatusMovedPermanently
-		if rq.Method != http.MethodGet {
-			code = http.StatusPermanentRedirect
-		}
-		http.Redirect(w, rq, urlPath, code)
+	if match.subtreeHandler != nil {
+		match.subtreeHandler.ServeHTTP(w, rq)
 		return
 	}
 
+	if cleaned != urlPath {
+		trailingSlashOnly := cleaned != "/" && cleaned+"/" == urlPath
+		redirect := trailingSlashOnly && r.RedirectTrailingSlash
+		redirect = redirect || (!trailingSlashOnly && r.RedirectFixedPath)
+		if redirect {
+			code := http.StatusMovedPermanently
+			if rq.Method != http.MethodGet {
+				code = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, rq, cleaned, code)
+			return
+		}
+	}
+
 	handler, methodAllowed := match.handlers[rq.Method]
 	if !methodAllowed {
 		r.handleMethodNotAllowed(w, rq, match)
@@ -197,9 +474,10 @@ func (r *Router) handleRequest(w http.ResponseWriter, rq *http.Request) {
 }
 
 func (r *Router) handleMethodNotAllowed(w http.ResponseWriter, rq *http.Request, node *node) {
-	w.Header().Set("Allow", node.allow())
+	allow := node.allow()
+	w.Header().Set("Allow", allow)
 	if rq.Method == http.MethodOptions {
-		r.AutoOptions.ServeHTTP(w, rq)
+		r.AutoOptions.ServeOptions(w, rq, strings.Split(allow, ", "))
 	} else {
 		r.MethodNotAllowed.ServeHTTP(w, rq)
 	}
@@ -3,6 +3,7 @@ package rush
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -210,6 +211,52 @@ func TestRouter_Matching(t *testing.T) {
 	}
 }
 
+func TestRouter_RegexParams(t *testing.T) {
+	r := New()
+
+	r.Get("/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id:" + r.PathValue("id")))
+	})
+	r.Get("/users/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("name:" + r.PathValue("name")))
+	})
+	r.Get("/files/{name:.+\\.pdf}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pdf:" + r.PathValue("name")))
+	})
+	r.Get("/posts/{id:int}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("post:" + r.PathValue("id")))
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/42", "id:42"},
+		{"/users/new", "name:new"},
+		{"/files/report.pdf", "pdf:report.pdf"},
+		{"/files/report.txt", ""},
+		{"/posts/7", "post:7"},
+		{"/posts/abc", ""},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if tt.want == "" {
+			if w.Code != http.StatusNotFound {
+				t.Errorf("path %q: expected 404, got %d", tt.path, w.Code)
+			}
+			continue
+		}
+
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("path %q: expected body %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
 func TestRouter_Overlap(t *testing.T) {
 	r := New()
 
@@ -249,6 +296,41 @@ func TestRouter_Overlap(t *testing.T) {
 	}
 }
 
+func TestRouter_DeepBacktracking(t *testing.T) {
+	r := New()
+
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("param:" + r.PathValue("id")))
+	})
+	r.Get("/users/new/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("static:" + r.PathValue("id")))
+	})
+	r.Get("/users/{id}/settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("settings:" + r.PathValue("id")))
+	})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/42", "param:42"},
+		{"/users/new/profile", "static:"},
+		// "new" first takes the static branch, which has no "settings"
+		// child, so matching backtracks into {id}.
+		{"/users/new/settings", "settings:new"},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("path %q: expected body %q, got %q", tt.path, tt.want, got)
+		}
+	}
+}
+
 func TestRouter_MethodNotAllowed(t *testing.T) {
 	r := New()
 
@@ -559,3 +641,391 @@ func TestRouter_RedirectTrailingSlash(t *testing.T) {
 		}
 	}
 }
+
+func TestRouter_HeadDiscardsBody(t *testing.T) {
+	r := New()
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	rq := httptest.NewRequest(http.MethodHead, "/ok", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, rq)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if body := w.Body.String(); body != "" {
+		t.Errorf("expected empty body for HEAD, got %q", body)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "5" {
+		t.Errorf("expected Content-Length %q, got %q", "5", cl)
+	}
+}
+
+func TestRouter_AutoOptionsCustom(t *testing.T) {
+	r := New()
+	var gotMethods []string
+	r.AutoOptions = OptionsHandlerFunc(func(w http.ResponseWriter, r *http.Request, allowedMethods []string) {
+		gotMethods = allowedMethods
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {}, http.MethodGet, http.MethodPost)
+
+	rq := httptest.NewRequest(http.MethodOptions, "/ok", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, rq)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if want := "GET, HEAD, OPTIONS, POST"; w.Header().Get("Access-Control-Allow-Methods") != want {
+		t.Errorf("expected Access-Control-Allow-Methods %q, got %q", want, w.Header().Get("Access-Control-Allow-Methods"))
+	}
+	if len(gotMethods) == 0 {
+		t.Error("expected AutoOptions to receive allowed methods")
+	}
+}
+
+func TestRouter_WalkAndURL(t *testing.T) {
+	r := New()
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	r.Get("/users/{id}", handler).Name("user.show")
+	r.Post("/users", handler)
+	r.Host("api.example.com").Get("/status", handler).Name("api.status")
+	r.Host("{tenant}.example.com").Get("/status", handler).Name("tenant.status")
+
+	seen := map[string]string{}
+	err := r.Walk(func(method, host, pattern string, handler http.Handler, middlewares []Middleware) error {
+		seen[method+" "+host+" "+pattern] = pattern
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk returned error: %v", err)
+	}
+
+	wantRoutes := []string{
+		"GET  /users/{id}", "HEAD  /users/{id}", "POST  /users",
+		"GET api.example.com /status", "HEAD api.example.com /status",
+		"GET {tenant}.example.com /status", "HEAD {tenant}.example.com /status",
+	}
+	for _, want := range wantRoutes {
+		if _, ok := seen[want]; !ok {
+			t.Errorf("Walk did not report route %q", want)
+		}
+	}
+
+	path, host, err := r.URL("user.show", "42")
+	if err != nil {
+		t.Fatalf("URL returned error: %v", err)
+	}
+	if path != "/users/42" {
+		t.Errorf("expected path %q, got %q", "/users/42", path)
+	}
+	if host != "" {
+		t.Errorf("expected no host restriction, got %q", host)
+	}
+
+	_, host, err = r.URL("api.status")
+	if err != nil {
+		t.Fatalf("URL returned error: %v", err)
+	}
+	if host != "api.example.com" {
+		t.Errorf("expected host %q, got %q", "api.example.com", host)
+	}
+
+	if _, _, err := r.URL("user.show"); err == nil {
+		t.Error("expected error for missing param, got nil")
+	}
+	if _, _, err := r.URL("unknown.route"); err == nil {
+		t.Error("expected error for unknown route name, got nil")
+	}
+}
+
+func TestRouter_Host(t *testing.T) {
+	r := New()
+
+	r.Host("api.example.com").Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api"))
+	})
+	r.Host("{tenant}.example.com").Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tenant:" + r.PathValue("tenant")))
+	})
+	r.Get("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default"))
+	})
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"api.example.com", "api"},
+		{"acme.example.com", "tenant:acme"},
+		{"other.invalid", "default"},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, "/status", nil)
+		rq.Host = tt.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("host %q: expected body %q, got %q", tt.host, tt.want, got)
+		}
+	}
+}
+
+func TestRouter_Schemes(t *testing.T) {
+	r := New()
+	r.TrustForwardedHeaders = true
+
+	r.Schemes("https").Get("/secure", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		proto string
+		code  int
+	}{
+		{"https", http.StatusOK},
+		{"http", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, "/secure", nil)
+		rq.Header.Set("X-Forwarded-Proto", tt.proto)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if w.Code != tt.code {
+			t.Errorf("proto %q: expected status %d, got %d", tt.proto, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_SchemesPerMethod(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	run := func(t *testing.T, r *Router, path string, tests []struct {
+		method string
+		proto  string
+		code   int
+	}) {
+		t.Helper()
+		for _, tt := range tests {
+			rq := httptest.NewRequest(tt.method, path, nil)
+			rq.Header.Set("X-Forwarded-Proto", tt.proto)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, rq)
+
+			if w.Code != tt.code {
+				t.Errorf("[%s proto=%s]: expected status %d, got %d", tt.method, tt.proto, tt.code, w.Code)
+			}
+		}
+	}
+
+	t.Run("restricted method registered before unrestricted method", func(t *testing.T) {
+		r := New()
+		r.TrustForwardedHeaders = true
+		r.Schemes("https").Get("/account", ok)
+		r.Post("/account", ok)
+
+		run(t, r, "/account", []struct {
+			method string
+			proto  string
+			code   int
+		}{
+			{http.MethodGet, "https", http.StatusOK},
+			{http.MethodGet, "http", http.StatusNotFound},
+			{http.MethodPost, "http", http.StatusOK},
+			{http.MethodPost, "https", http.StatusOK},
+		})
+	})
+
+	t.Run("unrestricted method registered before restricted method", func(t *testing.T) {
+		r := New()
+		r.TrustForwardedHeaders = true
+		r.Post("/account", ok)
+		r.Schemes("https").Get("/account", ok)
+
+		run(t, r, "/account", []struct {
+			method string
+			proto  string
+			code   int
+		}{
+			{http.MethodPost, "http", http.StatusOK},
+			{http.MethodPost, "https", http.StatusOK},
+			{http.MethodGet, "https", http.StatusOK},
+			{http.MethodGet, "http", http.StatusNotFound},
+		})
+	})
+
+	t.Run("two methods with different scheme restrictions", func(t *testing.T) {
+		r := New()
+		r.TrustForwardedHeaders = true
+		r.Schemes("https").Get("/account", ok)
+		r.Schemes("http").Post("/account", ok)
+
+		run(t, r, "/account", []struct {
+			method string
+			proto  string
+			code   int
+		}{
+			{http.MethodGet, "https", http.StatusOK},
+			{http.MethodGet, "http", http.StatusNotFound},
+			{http.MethodPost, "http", http.StatusOK},
+			{http.MethodPost, "https", http.StatusNotFound},
+		})
+	})
+}
+
+func TestRouter_Mount(t *testing.T) {
+	sub := http.NewServeMux()
+	sub.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong:" + r.URL.Path))
+	})
+
+	r := New()
+	r.Mount("/api", sub)
+	r.Get("/other", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/ping", http.StatusOK, "pong:/ping"},
+		{"/api/missing", http.StatusNotFound, ""},
+		{"/other", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if w.Code != tt.code {
+			t.Errorf("[%s] expected status %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if tt.body != "" {
+			if got := w.Body.String(); got != tt.body {
+				t.Errorf("[%s] expected body %q, got %q", tt.path, tt.body, got)
+			}
+		}
+	}
+}
+
+func TestRouter_MountHostAndSchemes(t *testing.T) {
+	sub := http.NewServeMux()
+	sub.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+
+	r := New()
+	r.TrustForwardedHeaders = true
+	r.Host("internal.example.com").Mount("/admin", sub)
+	r.Schemes("https").Mount("/secure", sub)
+
+	tests := []struct {
+		host  string
+		proto string
+		path  string
+		code  int
+	}{
+		{"internal.example.com", "", "/admin/ping", http.StatusOK},
+		{"other.example.com", "", "/admin/ping", http.StatusNotFound},
+		{"", "https", "/secure/ping", http.StatusOK},
+		{"", "http", "/secure/ping", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.host != "" {
+			rq.Host = tt.host
+		}
+		if tt.proto != "" {
+			rq.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if w.Code != tt.code {
+			t.Errorf("[host=%q proto=%q %s] expected status %d, got %d", tt.host, tt.proto, tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+func TestRouter_MountRouteConflict(t *testing.T) {
+	assertPanics := func(t *testing.T, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic, got none")
+			}
+		}()
+		fn()
+	}
+
+	t.Run("route registered under an existing mount", func(t *testing.T) {
+		r := New()
+		r.Mount("/api", http.NewServeMux())
+		assertPanics(t, func() {
+			r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+
+	t.Run("mount attached over an existing route", func(t *testing.T) {
+		r := New()
+		r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {})
+		assertPanics(t, func() {
+			r.Mount("/api", http.NewServeMux())
+		})
+	})
+
+	t.Run("mount attached to an already-mounted path", func(t *testing.T) {
+		r := New()
+		r.Mount("/api", http.NewServeMux())
+		assertPanics(t, func() {
+			r.Mount("/api", http.NewServeMux())
+		})
+	})
+}
+
+func TestRouter_RedirectFixedPath(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/api/v1/status", handler)
+
+	tests := []struct {
+		path     string
+		code     int
+		location string
+	}{
+		{"/api/v1/status", http.StatusOK, ""},
+		{"/api//v1///status", http.StatusMovedPermanently, "/api/v1/status"},
+		{"/api/v1/../v1/status", http.StatusMovedPermanently, "/api/v1/status"},
+		{"/not/found", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		rq := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, rq)
+
+		if tt.code != w.Code {
+			t.Errorf("[%s] expected status %d, got %d", tt.path, tt.code, w.Code)
+		}
+
+		if tt.location != "" {
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("[%s] expected redirect Location %q, got %q", tt.path, tt.location, got)
+			}
+		}
+	}
+}
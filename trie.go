@@ -3,17 +3,57 @@ package rush
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"slices"
 	"strings"
 )
 
 type node struct {
-	children      map[string]*node
-	handlers      map[string]http.Handler
-	paramChild    *node
-	wildcardChild *node
-	segment       string
-	allowHeader   string
+	children           map[string]*node
+	handlers           map[string]http.Handler
+	middlewares        map[string][]Middleware
+	paramChild         *node
+	paramRegexChildren []*node
+	paramRegex         *regexp.Regexp
+	wildcardChild      *node
+	subtreeHandler     http.Handler
+	// schemes holds each method's scheme restriction, keyed the same way
+	// as handlers/middlewares - a node is shared by every method
+	// registered on the same pattern, so a single node-wide slice would
+	// let one method's restriction leak onto (or clear) another's.
+	schemes map[string][]string
+	// mountSchemes is subtreeHandler's scheme restriction - a mounted
+	// subtree has no per-method handlers of its own to key by.
+	mountSchemes []string
+	segment      string
+	allowHeader  string
+	pattern      string
+	name         string
+	// host is the Host pattern the route was registered under, or "" for
+	// a route with no host restriction. Kept on the node so Router.Walk
+	// and Router.URL can report it without having to rediscover which
+	// host root a node belongs to.
+	host string
+}
+
+// paramShorthands maps common constraint shorthands to their expanded
+// regex form, e.g. "{id:int}" instead of "{id:[0-9]+}".
+var paramShorthands = map[string]string{
+	"int": "^[0-9]+$",
+}
+
+// compileParamConstraint compiles a param constraint (the part after ':' in
+// "{name:constraint}") into a regexp that matches the whole path segment.
+func compileParamConstraint(constraint string) *regexp.Regexp {
+	if expanded, ok := paramShorthands[constraint]; ok {
+		constraint = expanded
+	}
+	constraint = "^(?:" + constraint + ")$"
+	re, err := regexp.Compile(constraint)
+	if err != nil {
+		panic(fmt.Sprintf("rush: invalid parameter constraint %q: %v", constraint, err))
+	}
+	return re
 }
 
 func newNode(segment string) *node {
@@ -26,10 +66,33 @@ func newNode(segment string) *node {
 
 func (n *node) nextOrCreate(segment string) *node {
 	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
-		name := segment[1 : len(segment)-1]
+		name, constraint, hasConstraint := strings.Cut(segment[1:len(segment)-1], ":")
 		if name == "" {
 			panic("rush: empty parameter name '{}' is not allowed")
 		}
+
+		// A constrained param ("{id:[0-9]+}") gets its own branch
+		// alongside any sibling constrained params and the single
+		// unconstrained catch-all, so e.g. "{id:[0-9]+}" and "{name}"
+		// can coexist at the same level - find() tries each regex
+		// branch before falling back to the catch-all.
+		if hasConstraint {
+			re := compileParamConstraint(constraint)
+			for _, child := range n.paramRegexChildren {
+				if child.segment != name {
+					continue
+				}
+				if re.String() != child.paramRegex.String() {
+					panic(fmt.Sprintf("rush: conflicting constraints for parameter '%s'", name))
+				}
+				return child
+			}
+			child := newNode(name)
+			child.paramRegex = re
+			n.paramRegexChildren = append(n.paramRegexChildren, child)
+			return child
+		}
+
 		if n.paramChild == nil {
 			n.paramChild = newNode(name)
 		}
@@ -59,6 +122,12 @@ func (n *node) nextOrCreate(segment string) *node {
 	return next
 }
 
+// hasDescendants reports whether any route is registered below n - i.e.
+// whether mounting a subtree handler on n would shadow it.
+func (n *node) hasDescendants() bool {
+	return len(n.children) > 0 || n.paramChild != nil || len(n.paramRegexChildren) > 0 || n.wildcardChild != nil
+}
+
 func (n *node) allow() string {
 	if n.allowHeader == "" {
 		methods := make([]string, 0, len(n.handlers)+1)
@@ -77,36 +146,233 @@ func (n *node) allow() string {
 
 type trie struct {
 	root *node
+
+	// hostRoots holds one trie root per exact host pattern, and
+	// hostParams one per host pattern containing a "{name}" label
+	// (e.g. "{tenant}.example.com"). Routes registered without a host
+	// restriction live in root and are used when no host pattern matches.
+	hostRoots  map[string]*node
+	hostParams []*hostRoot
+
+	// names maps a route's Name to the terminal node it was registered
+	// on, for reverse URL generation via Router.URL.
+	names map[string]*node
+}
+
+// hostRoot is a host pattern ("{sub}.example.com") compiled to a regexp
+// that captures its {name} labels, paired with the trie root that holds
+// routes registered under that host.
+type hostRoot struct {
+	pattern *regexp.Regexp
+	names   []string
+	root    *node
 }
 
 func splitPath(path string) []string {
 	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
 }
 
-func (t *trie) insert(pattern string, handler http.Handler, methods ...string) {
+// compileHostPattern turns a host pattern into a regexp matching the
+// whole host, capturing each "{name}" label in order.
+func compileHostPattern(host string) (*regexp.Regexp, []string) {
+	labels := strings.Split(host, ".")
+	var names []string
+	var b strings.Builder
+	b.WriteString("^")
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteString(`\.`)
+		}
+		if strings.HasPrefix(label, "{") && strings.HasSuffix(label, "}") {
+			names = append(names, label[1:len(label)-1])
+			b.WriteString(`([^.]+)`)
+		} else {
+			b.WriteString(regexp.QuoteMeta(label))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String()), names
+}
+
+// rootForHost returns (creating if necessary) the trie root dedicated to
+// the given host pattern, which may contain "{name}" labels.
+func (t *trie) rootForHost(host string) *node {
+	if !strings.Contains(host, "{") {
+		if t.hostRoots == nil {
+			t.hostRoots = make(map[string]*node)
+		}
+		if n, ok := t.hostRoots[host]; ok {
+			return n
+		}
+		n := newNode(host)
+		t.hostRoots[host] = n
+		return n
+	}
+
+	pattern, names := compileHostPattern(host)
+	for _, hr := range t.hostParams {
+		if hr.pattern.String() == pattern.String() {
+			return hr.root
+		}
+	}
+	hr := &hostRoot{pattern: pattern, names: names, root: newNode(host)}
+	t.hostParams = append(t.hostParams, hr)
+	return hr.root
+}
+
+func (t *trie) insert(host, pattern string, handler http.Handler, methods ...string) *node {
+	root := t.root
+	if host != "" {
+		root = t.rootForHost(host)
+	}
+
 	segments := splitPath(pattern)
-	cur := t.root
+	cur := root
+	if cur.subtreeHandler != nil {
+		panic(fmt.Sprintf("rush: cannot register route %q - it is shadowed by a Mount on this path", pattern))
+	}
 	for i, seg := range segments {
 		if seg == "*" && i != len(segments)-1 {
 			panic("rush: wildcard '*' can only be at the end of the route")
 		}
 		cur = cur.nextOrCreate(seg)
+		if cur.subtreeHandler != nil {
+			panic(fmt.Sprintf("rush: cannot register route %q - it is shadowed by a Mount on an ancestor path", pattern))
+		}
 	}
 	for _, method := range methods {
 		cur.handlers[method] = handler
 	}
+	return cur
 }
 
-func (t *trie) lookup(path string, r *http.Request) *node {
-	return t.root.match(1, path, r)
+// mount registers handler as a subtree handler for every request under
+// prefix, creating any intermediate static nodes needed along the way.
+// Like insert, a non-empty host mounts into that host's dedicated root
+// instead of the default one.
+func (t *trie) mount(host, prefix string, handler http.Handler) *node {
+	root := t.root
+	if host != "" {
+		root = t.rootForHost(host)
+	}
+
+	segments := splitPath(prefix)
+	cur := root
+	for _, seg := range segments {
+		if cur.subtreeHandler != nil {
+			panic(fmt.Sprintf("rush: cannot mount %q - it is shadowed by a Mount on an ancestor path", prefix))
+		}
+		cur = cur.nextOrCreate(seg)
+	}
+	if cur.subtreeHandler != nil {
+		panic(fmt.Sprintf("rush: cannot mount %q - it is already mounted", prefix))
+	}
+	if len(cur.handlers) > 0 || cur.hasDescendants() {
+		panic(fmt.Sprintf("rush: cannot mount %q - a route already registered at or under this path would be shadowed", prefix))
+	}
+	cur.subtreeHandler = handler
+	return cur
+}
+
+func (t *trie) lookup(host, path string, r *http.Request) *node {
+	root := t.root
+	if n, ok := t.hostRoots[host]; ok {
+		root = n
+	} else {
+		for _, hr := range t.hostParams {
+			m := hr.pattern.FindStringSubmatch(host)
+			if m == nil {
+				continue
+			}
+			for i, name := range hr.names {
+				r.SetPathValue(name, m[i+1])
+			}
+			root = hr.root
+			break
+		}
+	}
+	return root.match(1, path, r)
+}
+
+// walk calls fn for every method registered on n or one of its
+// descendants, in a deterministic (sorted) order.
+func (n *node) walk(fn func(method, host, pattern string, handler http.Handler, middlewares []Middleware) error) error {
+	if len(n.handlers) > 0 {
+		methods := make([]string, 0, len(n.handlers))
+		for method := range n.handlers {
+			methods = append(methods, method)
+		}
+		slices.Sort(methods)
+		for _, method := range methods {
+			if err := fn(method, n.host, n.pattern, n.handlers[method], n.middlewares[method]); err != nil {
+				return err
+			}
+		}
+	}
+
+	children := make([]string, 0, len(n.children))
+	for seg := range n.children {
+		children = append(children, seg)
+	}
+	slices.Sort(children)
+	for _, seg := range children {
+		if err := n.children[seg].walk(fn); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range n.paramRegexChildren {
+		if err := child.walk(fn); err != nil {
+			return err
+		}
+	}
+	if n.paramChild != nil {
+		if err := n.paramChild.walk(fn); err != nil {
+			return err
+		}
+	}
+	if n.wildcardChild != nil {
+		if err := n.wildcardChild.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paramCapture is a param binding tentatively made while backtracking
+// through the trie; it's only applied to the request if the branch that
+// made it turns out to be the winning match.
+type paramCapture struct {
+	name  string
+	value string
 }
 
 func (n *node) match(i int, path string, r *http.Request) *node {
+	m, captures := n.find(i, path, nil)
+	if m == nil {
+		return nil
+	}
+	for _, c := range captures {
+		r.SetPathValue(c.name, c.value)
+	}
+	return m
+}
+
+// find walks the trie looking for a match, threading param bindings made
+// along the way through captures instead of writing them to the request -
+// so a binding from a branch that ultimately fails (e.g. "/users/{id}"
+// backtracking off of "/users/new/profile") never leaks into the request
+// that a sibling branch goes on to win with.
+func (n *node) find(i int, path string, captures []paramCapture) (*node, []paramCapture) {
+	if n.subtreeHandler != nil {
+		return n, captures
+	}
+
 	if i >= len(path) {
 		if len(n.handlers) > 0 {
-			return n
+			return n, captures
 		}
-		return nil
+		return nil, nil
 	}
 
 	end := i
@@ -117,22 +383,31 @@ func (n *node) match(i int, path string, r *http.Request) *node {
 	next := end + 1
 
 	if child, ok := n.children[segment]; ok {
-		if m := child.match(next, path, r); m != nil {
-			return m
+		if m, c := child.find(next, path, captures); m != nil {
+			return m, c
+		}
+	}
+
+	for _, child := range n.paramRegexChildren {
+		if !child.paramRegex.MatchString(segment) {
+			continue
+		}
+		bound := append(slices.Clone(captures), paramCapture{child.segment, segment})
+		if m, c := child.find(next, path, bound); m != nil {
+			return m, c
 		}
 	}
 
 	if n.paramChild != nil {
-		r.SetPathValue(n.paramChild.segment, segment)
-		if m := n.paramChild.match(next, path, r); m != nil {
-			return m
+		bound := append(slices.Clone(captures), paramCapture{n.paramChild.segment, segment})
+		if m, c := n.paramChild.find(next, path, bound); m != nil {
+			return m, c
 		}
-		r.SetPathValue(n.paramChild.segment, "")
 	}
 
 	if n.wildcardChild != nil {
-		return n.wildcardChild
+		return n.wildcardChild, captures
 	}
 
-	return nil
+	return nil, nil
 }